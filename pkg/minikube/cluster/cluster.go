@@ -18,6 +18,7 @@ package cluster
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -25,6 +26,7 @@ import (
 	"net"
 	"os/exec"
 	"regexp"
+	"sync"
 	"time"
 
 	"github.com/docker/machine/libmachine"
@@ -50,6 +52,10 @@ const (
 	defaultVirtualboxNicType = "virtio"
 )
 
+// machineNameMu serializes the active-profile override in createHost, so that StartCluster's
+// concurrent per-node goroutines don't race setting/restoring that shared global.
+var machineNameMu sync.Mutex
+
 //This init function is used to set the logtostderr variable to false so that INFO level log info does not clutter the CLI
 //INFO lvl logging is displayed due to the kubernetes api calling flag.Set("logtostderr", "true") in its init()
 //see: https://github.com/kubernetes/kubernetes/blob/master/pkg/kubectl/util/logs/logs.go#L32-L34
@@ -61,20 +67,29 @@ func init() {
 }
 
 // StartHost starts a host VM.
-func StartHost(api libmachine.API, config cfg.MachineConfig) (*host.Host, error) {
-	exists, err := api.Exists(cfg.GetMachineName())
+func StartHost(ctx context.Context, api libmachine.API, config cfg.MachineConfig) (*host.Host, error) {
+	return StartNamedHost(ctx, api, cfg.GetMachineName(), config)
+}
+
+// StartNamedHost starts a host VM under the given machine name, allowing callers
+// (such as StartCluster) to provision more than one node from the same config template.
+func StartNamedHost(ctx context.Context, api libmachine.API, machineName string, config cfg.MachineConfig) (h *host.Host, err error) {
+	emitter := emitterFromContext(ctx)
+	defer emitErrorOnFailure(emitter, machineName, &err)
+
+	exists, err := api.Exists(machineName)
 	if err != nil {
-		return nil, errors.Wrapf(err, "machine name: %s", cfg.GetMachineName())
+		return nil, errors.Wrapf(err, "machine name: %s", machineName)
 	}
 	if !exists {
 		glog.Infoln("Machine does not exist... provisioning new machine")
 		glog.Infof("Provisioning machine with config: %+v", config)
-		return createHost(api, config)
+		return createHost(ctx, api, machineName, config)
 	}
 
 	glog.Infoln("Skipping create...Using existing machine configuration")
 
-	h, err := api.Load(cfg.GetMachineName())
+	h, err = api.Load(machineName)
 	if err != nil {
 		return nil, errors.Wrap(err, "Error loading existing host. Please try running [minikube delete], then run [minikube start] again.")
 	}
@@ -82,11 +97,11 @@ func StartHost(api libmachine.API, config cfg.MachineConfig) (*host.Host, error)
 	if h.Driver.DriverName() != config.VMDriver {
 		console.Out("\n")
 		console.Warning("Ignoring --vm-driver=%s, as the existing %q VM was created using the %s driver.",
-			config.VMDriver, cfg.GetMachineName(), h.Driver.DriverName())
+			config.VMDriver, machineName, h.Driver.DriverName())
 		console.Warning("To switch drivers, you may create a new VM using `minikube start -p <name> --vm-driver=%s`", config.VMDriver)
-		console.Warning("Alternatively, you may delete the existing VM using `minikube delete -p %s`", cfg.GetMachineName())
+		console.Warning("Alternatively, you may delete the existing VM using `minikube delete -p %s`", machineName)
 		console.Out("\n")
-	} else if exists && cfg.GetMachineName() == constants.DefaultMachineName {
+	} else if exists && machineName == constants.DefaultMachineName {
 		console.OutStyle("tip", "Tip: To create a new cluster, use 'minikube start -p <new name>' or use 'minikube delete' to delete this one.")
 	}
 
@@ -97,9 +112,11 @@ func StartHost(api libmachine.API, config cfg.MachineConfig) (*host.Host, error)
 	}
 
 	if s == state.Running {
-		console.OutStyle("running", "Re-using the currently running %s VM for %q ...", h.Driver.DriverName(), cfg.GetMachineName())
+		emitter.Emit(Event{Type: VMRunning, Driver: h.Driver.DriverName(), MachineName: machineName,
+			Message: fmt.Sprintf("Re-using the currently running %s VM for %q ...", h.Driver.DriverName(), machineName)})
 	} else {
-		console.OutStyle("restarting", "Restarting existing %s VM for %q ...", h.Driver.DriverName(), cfg.GetMachineName())
+		emitter.Emit(Event{Type: VMStarting, Driver: h.Driver.DriverName(), MachineName: machineName,
+			Message: fmt.Sprintf("Restarting existing %s VM for %q ...", h.Driver.DriverName(), machineName)})
 		if err := h.Driver.Start(); err != nil {
 			return nil, errors.Wrap(err, "start")
 		}
@@ -112,7 +129,7 @@ func StartHost(api libmachine.API, config cfg.MachineConfig) (*host.Host, error)
 	glog.Infof("engine options: %+v", e)
 
 	// Slightly counter-intuitive, but this is what DetectProvisioner & ConfigureAuth block on.
-	console.OutStyle("waiting", "Waiting for SSH access ...")
+	emitter.Emit(Event{Type: SSHReady, MachineName: machineName, Message: "Waiting for SSH access ..."})
 
 	if len(e.Env) > 0 {
 		h.HostOptions.EngineOptions.Env = e.Env
@@ -120,6 +137,7 @@ func StartHost(api libmachine.API, config cfg.MachineConfig) (*host.Host, error)
 		if err != nil {
 			return nil, errors.Wrap(err, "detecting provisioner")
 		}
+		emitter.Emit(Event{Type: ProvisionStarted, MachineName: machineName, Message: "Provisioning host ..."})
 		if err := provisioner.Provision(*h.HostOptions.SwarmOptions, *h.HostOptions.AuthOptions, *h.HostOptions.EngineOptions); err != nil {
 			return nil, errors.Wrap(err, "provision")
 		}
@@ -148,57 +166,81 @@ func tryPowerOff(h *host.Host) {
 		return
 	}
 
-	console.OutStyle("shutdown", "Powering off %q via SSH ...", cfg.GetMachineName())
+	console.OutStyle("shutdown", "Powering off %q via SSH ...", h.Name)
 	out, err := h.RunSSHCommand("sudo poweroff")
 	// poweroff always results in an error, since the host disconnects.
 	glog.Infof("poweroff result: out=%s, err=%v", out, err)
 }
 
 // StopHost stops the host VM, saving state to disk.
-func StopHost(api libmachine.API) error {
-	host, err := api.Load(cfg.GetMachineName())
+func StopHost(ctx context.Context, api libmachine.API) error {
+	return StopNamedHost(ctx, api, cfg.GetMachineName())
+}
+
+// StopNamedHost stops the host VM for the given machine name.
+func StopNamedHost(ctx context.Context, api libmachine.API, machineName string) (err error) {
+	emitter := emitterFromContext(ctx)
+	defer emitErrorOnFailure(emitter, machineName, &err)
+
+	host, err := api.Load(machineName)
 	if err != nil {
 		return errors.Wrapf(err, "load")
 	}
-	console.OutStyle("stopping", "Stopping %q in %s ...", cfg.GetMachineName(), host.DriverName)
+	emitter.Emit(Event{Type: VMStopping, Driver: host.DriverName, MachineName: machineName,
+		Message: fmt.Sprintf("Stopping %q in %s ...", machineName, host.DriverName)})
+	start := time.Now()
 	if err := host.Stop(); err != nil {
 		alreadyInStateError, ok := err.(mcnerror.ErrHostAlreadyInState)
 		if ok && alreadyInStateError.State == state.Stopped {
 			return nil
 		}
-		return &util.RetriableError{Err: errors.Wrapf(err, "Stop: %s", cfg.GetMachineName())}
+		return &util.RetriableError{Err: errors.Wrapf(err, "Stop: %s", machineName)}
 	}
+	emitter.Emit(Event{Type: VMStopped, Driver: host.DriverName, MachineName: machineName, Duration: time.Since(start),
+		Message: fmt.Sprintf("Stopped %q", machineName)})
 	return nil
 }
 
 // DeleteHost deletes the host VM.
-func DeleteHost(api libmachine.API) error {
-	host, err := api.Load(cfg.GetMachineName())
+func DeleteHost(ctx context.Context, api libmachine.API) error {
+	return DeleteNamedHost(ctx, api, cfg.GetMachineName())
+}
+
+// DeleteNamedHost deletes the host VM for the given machine name.
+func DeleteNamedHost(ctx context.Context, api libmachine.API, machineName string) (err error) {
+	emitter := emitterFromContext(ctx)
+	defer emitErrorOnFailure(emitter, machineName, &err)
+
+	host, err := api.Load(machineName)
 	if err != nil {
 		return errors.Wrap(err, "load")
 	}
 	tryPowerOff(host)
-	console.OutStyle("deleting-host", "Deleting %q from %s ...", cfg.GetMachineName(), host.DriverName)
+	emitter.Emit(Event{Type: VMDeleting, Driver: host.DriverName, MachineName: machineName,
+		Message: fmt.Sprintf("Deleting %q from %s ...", machineName, host.DriverName)})
+	start := time.Now()
 	if err := host.Driver.Remove(); err != nil {
 		return errors.Wrap(err, "host remove")
 	}
-	if err := api.Remove(cfg.GetMachineName()); err != nil {
+	if err := api.Remove(machineName); err != nil {
 		return errors.Wrap(err, "api remove")
 	}
+	emitter.Emit(Event{Type: VMDeleted, Driver: host.DriverName, MachineName: machineName, Duration: time.Since(start),
+		Message: fmt.Sprintf("Deleted %q", machineName)})
 	return nil
 }
 
 // GetHostStatus gets the status of the host VM.
-func GetHostStatus(api libmachine.API) (string, error) {
-	exists, err := api.Exists(cfg.GetMachineName())
+func GetHostStatus(api libmachine.API, machineName string) (string, error) {
+	exists, err := api.Exists(machineName)
 	if err != nil {
-		return "", errors.Wrapf(err, "%s exists", cfg.GetMachineName())
+		return "", errors.Wrapf(err, "%s exists", machineName)
 	}
 	if !exists {
 		return state.None.String(), nil
 	}
 
-	host, err := api.Load(cfg.GetMachineName())
+	host, err := api.Load(machineName)
 	if err != nil {
 		return "", errors.Wrapf(err, "load")
 	}
@@ -266,13 +308,18 @@ To disable this message, run [minikube config set WantShowDriverDeprecationNotif
 	return nil
 }
 
-func createHost(api libmachine.API, config cfg.MachineConfig) (*host.Host, error) {
-	err := preCreateHost(&config)
+// createHost is only ever called from StartNamedHost, whose own defer already emits an
+// Error event for any failure here, so createHost reports success only.
+func createHost(ctx context.Context, api libmachine.API, machineName string, config cfg.MachineConfig) (h *host.Host, err error) {
+	emitter := emitterFromContext(ctx)
+
+	err = preCreateHost(&config)
 	if err != nil {
 		return nil, err
 	}
 
-	console.OutStyle("starting-vm", "Creating %s VM (CPUs=%d, Memory=%dMB, Disk=%dMB) ...", config.VMDriver, config.CPUs, config.Memory, config.DiskSize)
+	emitter.Emit(Event{Type: VMCreating, Driver: config.VMDriver, MachineName: machineName,
+		Message: fmt.Sprintf("Creating %s VM (CPUs=%d, Memory=%dMB, Disk=%dMB) ...", config.VMDriver, config.CPUs, config.Memory, config.DiskSize)})
 	def, err := registry.Driver(config.VMDriver)
 	if err != nil {
 		if err == registry.ErrDriverNotFound {
@@ -285,24 +332,38 @@ func createHost(api libmachine.API, config cfg.MachineConfig) (*host.Host, error
 		if err := config.Downloader.CacheMinikubeISOFromURL(config.MinikubeISO); err != nil {
 			return nil, errors.Wrap(err, "unable to cache ISO")
 		}
-	}
-
+		emitter.Emit(Event{Type: ISOCached, MachineName: machineName, Message: "Downloaded and cached the minikube ISO"})
+	}
+
+	// ConfigCreator bakes the active profile (cfg.GetMachineName(), a package-level/viper
+	// global) into the driver struct it returns, not machineName. Left alone, every concurrent
+	// goroutine in StartCluster would build a driver pointed at the same underlying VM name and
+	// collide in the hypervisor. Point the active profile at this node for the duration of the
+	// driver construction so the marshaled driver itself — what libmachine actually uses to name
+	// the VM — gets machineName. machineNameMu serializes this across StartCluster's goroutines,
+	// since the active profile is shared, unsynchronized global state.
+	machineNameMu.Lock()
+	previousProfile := viper.GetString("profile")
+	viper.Set("profile", machineName)
 	driver := def.ConfigCreator(config)
-
 	data, err := json.Marshal(driver)
+	viper.Set("profile", previousProfile)
+	machineNameMu.Unlock()
 	if err != nil {
 		return nil, errors.Wrap(err, "marshal")
 	}
 
-	h, err := api.NewHost(config.VMDriver, data)
+	h, err = api.NewHost(config.VMDriver, data)
 	if err != nil {
 		return nil, errors.Wrap(err, "new host")
 	}
+	h.Name = machineName
 
 	h.HostOptions.AuthOptions.CertDir = constants.GetMinipath()
 	h.HostOptions.AuthOptions.StorePath = constants.GetMinipath()
 	h.HostOptions.EngineOptions = engineOptions(config)
 
+	start := time.Now()
 	if err := api.Create(h); err != nil {
 		// Wait for all the logs to reach the client
 		time.Sleep(2 * time.Second)
@@ -312,6 +373,8 @@ func createHost(api libmachine.API, config cfg.MachineConfig) (*host.Host, error
 	if err := api.Save(h); err != nil {
 		return nil, errors.Wrap(err, "save")
 	}
+	emitter.Emit(Event{Type: VMCreated, Driver: config.VMDriver, MachineName: machineName, Duration: time.Since(start),
+		Message: fmt.Sprintf("Created %q", machineName)})
 	return h, nil
 }
 
@@ -337,30 +400,6 @@ func GetHostDockerEnv(api libmachine.API) (map[string]string, error) {
 	return envMap, nil
 }
 
-// MountHost runs the mount command from the 9p client on the VM to the 9p server on the host
-func MountHost(api libmachine.API, ip net.IP, path, port, mountVersion string, uid, gid, msize int) error {
-	host, err := CheckIfHostExistsAndLoad(api, cfg.GetMachineName())
-	if err != nil {
-		return errors.Wrap(err, "Error checking that api exists and loading it")
-	}
-	if ip == nil {
-		ip, err = GetVMHostIP(host)
-		if err != nil {
-			return errors.Wrap(err, "Error getting the host IP address to use from within the VM")
-		}
-	}
-	host.RunSSHCommand(GetMountCleanupCommand(path))
-	mountCmd, err := GetMountCommand(ip, path, port, mountVersion, uid, gid, msize)
-	if err != nil {
-		return errors.Wrap(err, "mount command")
-	}
-	_, err = host.RunSSHCommand(mountCmd)
-	if err != nil {
-		return errors.Wrap(err, "running mount")
-	}
-	return nil
-}
-
 // GetVMHostIP gets the ip address to be used for mapping host -> VM and VM -> host
 func GetVMHostIP(host *host.Host) (net.IP, error) {
 	switch host.DriverName {
@@ -452,7 +491,7 @@ func CreateSSHShell(api libmachine.API, args []string) error {
 // EnsureMinikubeRunningOrExit checks that minikube has a status available and that
 // the status is `Running`, otherwise it will exit
 func EnsureMinikubeRunningOrExit(api libmachine.API, exitStatus int) {
-	s, err := GetHostStatus(api)
+	s, err := GetHostStatus(api, cfg.GetMachineName())
 	if err != nil {
 		exit.WithError("Error getting machine status", err)
 	}
@@ -0,0 +1,206 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"k8s.io/minikube/pkg/minikube/console"
+)
+
+// EventType identifies a stage of a cluster lifecycle operation.
+type EventType string
+
+const (
+	// VMCreating fires when a new VM is about to be provisioned.
+	VMCreating EventType = "VMCreating"
+	// ISOCached fires once the minikube ISO has been downloaded and cached locally.
+	ISOCached EventType = "ISOCached"
+	// VMStarting fires when an existing, stopped VM is being restarted.
+	VMStarting EventType = "VMStarting"
+	// VMRunning fires when a VM is found already running and is being reused as-is.
+	VMRunning EventType = "VMRunning"
+	// SSHReady fires when StartNamedHost, reusing an existing machine, begins waiting for the
+	// host to become reachable over SSH; it is emitted before that wait, not after it.
+	SSHReady EventType = "SSHReady"
+	// ProvisionStarted fires when the docker-machine provisioner begins configuring the guest.
+	ProvisionStarted EventType = "ProvisionStarted"
+	// KubeadmInitStarted fires when `kubeadm init` begins on the control-plane node.
+	KubeadmInitStarted EventType = "KubeadmInitStarted"
+	// KubeadmInitDone fires once `kubeadm init` has completed; Event.Duration is how long it took.
+	KubeadmInitDone EventType = "KubeadmInitDone"
+	// KubeadmJoinStarted fires when `kubeadm join` begins on a worker node.
+	KubeadmJoinStarted EventType = "KubeadmJoinStarted"
+	// KubeadmJoinDone fires once `kubeadm join` has completed; Event.Duration is how long it took.
+	KubeadmJoinDone EventType = "KubeadmJoinDone"
+	// VMStopping fires when a VM is being stopped.
+	VMStopping EventType = "VMStopping"
+	// VMDeleting fires when a VM is being deleted.
+	VMDeleting EventType = "VMDeleting"
+	// VMCreated fires once a new VM has finished provisioning; Event.Duration is the time
+	// api.Create and the following api.Save took together.
+	VMCreated EventType = "VMCreated"
+	// VMStopped fires once a VM has finished stopping; Event.Duration is the time it took.
+	VMStopped EventType = "VMStopped"
+	// VMDeleted fires once a VM has been removed; Event.Duration is the time it took.
+	VMDeleted EventType = "VMDeleted"
+	// MountEstablished reports that a host mount has been mounted on the guest. The emitters
+	// render it, but the mount functions in this package take no context and do not emit it.
+	MountEstablished EventType = "MountEstablished"
+	// Error fires when a lifecycle operation fails; Event.Err carries the cause.
+	Error EventType = "Error"
+)
+
+// Event is a single, structured point-in-time report from a cluster lifecycle operation.
+// Driver and MachineName identify which node it came from; Duration and Err are only set
+// where meaningful for the given Type.
+type Event struct {
+	Type        EventType     `json:"type"`
+	Driver      string        `json:"driver,omitempty"`
+	MachineName string        `json:"machineName,omitempty"`
+	Message     string        `json:"message"`
+	Duration    time.Duration `json:"durationNs,omitempty"`
+	Err         error         `json:"error,omitempty"`
+}
+
+// EventEmitter receives Events as cluster operations progress. Implementations must be
+// safe to call from multiple goroutines, since StartCluster provisions nodes concurrently.
+type EventEmitter interface {
+	Emit(Event)
+}
+
+// consoleEmitter is the default EventEmitter: it renders events as human-readable
+// console.OutStyle output.
+type consoleEmitter struct{}
+
+// NewConsoleEmitter returns the default EventEmitter, which prints human-readable progress
+// via console.OutStyle. Progress messages StartHost/StopHost/DeleteHost printed directly
+// before keep their text and style; ISOCached, ProvisionStarted, the completion events
+// (with their durations) and Error events print additional lines.
+func NewConsoleEmitter() EventEmitter {
+	return consoleEmitter{}
+}
+
+func (consoleEmitter) Emit(e Event) {
+	switch e.Type {
+	case VMCreating:
+		console.OutStyle("starting-vm", e.Message)
+	case ISOCached:
+		console.OutStyle("success", e.Message)
+	case VMStarting:
+		console.OutStyle("restarting", e.Message)
+	case VMRunning:
+		console.OutStyle("running", e.Message)
+	case SSHReady:
+		console.OutStyle("waiting", e.Message)
+	case ProvisionStarted:
+		console.OutStyle("waiting", e.Message)
+	case KubeadmInitStarted, KubeadmJoinStarted:
+		console.OutStyle("waiting", e.Message)
+	case VMStopping:
+		console.OutStyle("stopping", e.Message)
+	case VMDeleting:
+		console.OutStyle("deleting-host", e.Message)
+	case VMCreated, VMStopped, VMDeleted, KubeadmInitDone, KubeadmJoinDone:
+		console.OutStyle("success", "%s (%s)", e.Message, e.Duration)
+	case MountEstablished:
+		console.OutStyle("success", e.Message)
+	case Error:
+		console.Warning(e.Message)
+	default:
+		console.OutStyle("waiting", e.Message)
+	}
+}
+
+// jsonEmitter writes each Event as a single line of JSON, for CI systems and IDE
+// integrations to parse progress from (`--output=json`).
+type jsonEmitter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewJSONEmitter returns an EventEmitter that writes newline-delimited JSON to w.
+func NewJSONEmitter(w io.Writer) EventEmitter {
+	return &jsonEmitter{enc: json.NewEncoder(w)}
+}
+
+func (j *jsonEmitter) Emit(e Event) {
+	type wireEvent struct {
+		Type        EventType `json:"type"`
+		Driver      string    `json:"driver,omitempty"`
+		MachineName string    `json:"machineName,omitempty"`
+		Message     string    `json:"message"`
+		DurationMs  int64     `json:"durationMs,omitempty"`
+		Error       string    `json:"error,omitempty"`
+	}
+	we := wireEvent{
+		Type:        e.Type,
+		Driver:      e.Driver,
+		MachineName: e.MachineName,
+		Message:     e.Message,
+		DurationMs:  e.Duration.Nanoseconds() / int64(time.Millisecond),
+	}
+	if e.Err != nil {
+		we.Error = e.Err.Error()
+	}
+	// Best-effort: a malformed event stream shouldn't abort the operation it's reporting on.
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	_ = j.enc.Encode(we)
+}
+
+// NewEmitter returns the JSON emitter when output == "json", otherwise the default
+// human-readable console emitter. This is what `--output=json` selects between.
+func NewEmitter(output string) EventEmitter {
+	if output == "json" {
+		return NewJSONEmitter(os.Stdout)
+	}
+	return NewConsoleEmitter()
+}
+
+type emitterContextKey struct{}
+
+// WithEmitter attaches an EventEmitter to ctx, for StartHost/StopHost/DeleteHost and the
+// multi-node orchestrators to report their progress through. The driver-mismatch warnings
+// and profile tip in StartNamedHost and the power-off notice in tryPowerOff still print
+// directly to the console.
+func WithEmitter(ctx context.Context, e EventEmitter) context.Context {
+	return context.WithValue(ctx, emitterContextKey{}, e)
+}
+
+// emitterFromContext returns the EventEmitter attached to ctx, or the default
+// human-readable console emitter if none was attached.
+func emitterFromContext(ctx context.Context) EventEmitter {
+	if e, ok := ctx.Value(emitterContextKey{}).(EventEmitter); ok {
+		return e
+	}
+	return NewConsoleEmitter()
+}
+
+// emitErrorOnFailure emits an Error event for *err, if non-nil. It's meant to be used with
+// defer over a function's named error return, e.g. `defer emitErrorOnFailure(emitter, name, &err)`,
+// so every exported lifecycle function reports its own failures exactly once.
+func emitErrorOnFailure(emitter EventEmitter, machineName string, err *error) {
+	if *err != nil {
+		emitter.Emit(Event{Type: Error, MachineName: machineName, Message: (*err).Error(), Err: *err})
+	}
+}
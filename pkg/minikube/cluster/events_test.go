@@ -0,0 +1,68 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestJSONEmitterEmit(t *testing.T) {
+	var buf bytes.Buffer
+	e := NewJSONEmitter(&buf)
+
+	e.Emit(Event{
+		Type:        VMCreated,
+		Driver:      "virtualbox",
+		MachineName: "minikube",
+		Message:     "Created \"minikube\"",
+		Duration:    1500 * time.Millisecond,
+	})
+	e.Emit(Event{
+		Type:        Error,
+		MachineName: "minikube",
+		Message:     "create failed",
+		Err:         errors.New("create failed"),
+	})
+
+	dec := json.NewDecoder(&buf)
+
+	var created map[string]interface{}
+	if err := dec.Decode(&created); err != nil {
+		t.Fatalf("decoding first event: %v", err)
+	}
+	if created["durationMs"] != float64(1500) {
+		t.Errorf("durationMs = %v, want 1500", created["durationMs"])
+	}
+	if created["type"] != string(VMCreated) {
+		t.Errorf("type = %v, want %v", created["type"], VMCreated)
+	}
+
+	var failed map[string]interface{}
+	if err := dec.Decode(&failed); err != nil {
+		t.Fatalf("decoding second event: %v", err)
+	}
+	if failed["error"] != "create failed" {
+		t.Errorf("error = %v, want %q", failed["error"], "create failed")
+	}
+	if _, ok := failed["durationMs"]; ok {
+		t.Errorf("durationMs should be omitted when zero, got %v", failed["durationMs"])
+	}
+}
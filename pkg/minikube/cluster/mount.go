@@ -0,0 +1,475 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/machine/libmachine"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/pkg/errors"
+	cfg "k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/console"
+	"k8s.io/minikube/pkg/minikube/constants"
+)
+
+// MountType identifies which protocol a mount is served over.
+type MountType string
+
+const (
+	// NinePMountType is the default, built into the minikube ISO's guest kernel.
+	NinePMountType MountType = "9p"
+	// SSHFSMountType reverse-tunnels the host path to the guest over the existing SSH client.
+	SSHFSMountType MountType = "sshfs"
+	// CIFSMountType serves the host path from a small Samba server and mounts it with cifs.
+	CIFSMountType MountType = "cifs"
+)
+
+// MountConfig collects the parameters needed to establish a mount, independent of protocol.
+type MountConfig struct {
+	Type    MountType
+	IP      net.IP
+	Path    string
+	Port    string
+	Version string
+	UID     int
+	GID     int
+	Msize   int
+}
+
+// UnsupportedMountTypeError is returned by a MountProvider when the guest ISO lacks what
+// the protocol needs and installing it also failed, or (for sshfs) when the host runs no
+// sshd to tunnel to, so that callers can fall back to a different protocol instead of
+// failing the mount outright.
+type UnsupportedMountTypeError struct {
+	Type   MountType
+	Reason string
+}
+
+func (e *UnsupportedMountTypeError) Error() string {
+	return fmt.Sprintf("mount type %q is not supported by this guest: %s", e.Type, e.Reason)
+}
+
+// MountProvider implements a single mount protocol: installing anything required on the
+// guest, building the mount command to run over SSH, and cleaning up on unmount.
+type MountProvider interface {
+	// Prepare installs or verifies any guest-side dependencies, installing them if missing.
+	// It returns *UnsupportedMountTypeError if the guest ISO can't support this protocol
+	// even after attempting an install, or if a host-side prerequisite is missing.
+	Prepare(h *host.Host, mc MountConfig) error
+	// Command returns the command to run over SSH to establish the mount.
+	Command(mc MountConfig) (string, error)
+	// Cleanup tears down anything Prepare/Command set up (host-side processes, tunnels, ...)
+	// and returns the command to run over SSH to unmount on the guest.
+	Cleanup(mc MountConfig) string
+}
+
+var mountProviders = map[MountType]MountProvider{
+	NinePMountType: ninePMountProvider{},
+	SSHFSMountType: sshfsMountProvider{},
+	CIFSMountType:  cifsMountProvider{},
+}
+
+// MountHost runs the mount command from the 9p client on the VM to the 9p server on the host.
+// Deprecated: use MountHostWithConfig, which supports pluggable protocols.
+func MountHost(api libmachine.API, ip net.IP, path, port, mountVersion string, uid, gid, msize int) error {
+	return MountNamedHost(api, cfg.GetMachineName(), ip, path, port, mountVersion, uid, gid, msize)
+}
+
+// MountNamedHost runs the mount command on the given node, so that mounts in a multi-node
+// cluster can target a specific node rather than always the active profile's machine.
+// Deprecated: use MountNamedHostWithConfig, which supports pluggable protocols.
+func MountNamedHost(api libmachine.API, machineName string, ip net.IP, path, port, mountVersion string, uid, gid, msize int) error {
+	return MountNamedHostWithConfig(api, machineName, MountConfig{
+		Type:    NinePMountType,
+		IP:      ip,
+		Path:    path,
+		Port:    port,
+		Version: mountVersion,
+		UID:     uid,
+		GID:     gid,
+		Msize:   msize,
+	})
+}
+
+// MountHostWithConfig mounts a host path on the active profile's machine using the protocol
+// selected in mc.Type, falling back to 9p if the guest doesn't support it.
+func MountHostWithConfig(api libmachine.API, mc MountConfig) error {
+	return MountNamedHostWithConfig(api, cfg.GetMachineName(), mc)
+}
+
+// MountNamedHostWithConfig mounts a host path on the given node using the protocol selected
+// in mc.Type (`--mount-type=9p|sshfs|cifs`). If the guest ISO doesn't support the requested
+// protocol, it falls back to 9p automatically and warns the user.
+func MountNamedHostWithConfig(api libmachine.API, machineName string, mc MountConfig) error {
+	h, err := CheckIfHostExistsAndLoad(api, machineName)
+	if err != nil {
+		return errors.Wrap(err, "Error checking that api exists and loading it")
+	}
+	if mc.IP == nil {
+		mc.IP, err = GetVMHostIP(h)
+		if err != nil {
+			return errors.Wrap(err, "Error getting the host IP address to use from within the VM")
+		}
+	}
+
+	provider, ok := mountProviders[mc.Type]
+	if !ok {
+		return errors.Errorf("unknown mount type: %q", mc.Type)
+	}
+
+	if err := provider.Prepare(h, mc); err != nil {
+		if !shouldFallbackToNineP(err, mc.Type) {
+			return errors.Wrap(err, "preparing mount")
+		}
+		console.Warning("%v; falling back to 9p", err)
+		provider = mountProviders[NinePMountType]
+		mc.Type = NinePMountType
+		if err := provider.Prepare(h, mc); err != nil {
+			return errors.Wrap(err, "preparing fallback 9p mount")
+		}
+	}
+
+	if mc.Type == NinePMountType {
+		// 9p's Cleanup just unmounts whatever's stale at mc.Path, which is safe to run
+		// unconditionally before a fresh mount. sshfs/cifs's Cleanup tears down the host-side
+		// tunnel/server Prepare just (re)started, so running it here would undo Prepare's work
+		// before Command() even runs.
+		h.RunSSHCommand(provider.Cleanup(mc))
+	}
+
+	mountCmd, err := provider.Command(mc)
+	if err != nil {
+		return errors.Wrap(err, "mount command")
+	}
+	if _, err := h.RunSSHCommand(mountCmd); err != nil {
+		return errors.Wrap(err, "running mount")
+	}
+	return nil
+}
+
+// shouldFallbackToNineP reports whether a Prepare failure warrants retrying with the 9p
+// provider rather than failing the mount outright: only when the guest genuinely lacks
+// support for the requested protocol, and that protocol wasn't 9p itself.
+func shouldFallbackToNineP(err error, mcType MountType) bool {
+	_, unsupported := err.(*UnsupportedMountTypeError)
+	return unsupported && mcType != NinePMountType
+}
+
+// guestHasCommand checks whether a binary is present on the guest's PATH.
+func guestHasCommand(h *host.Host, cmd string) bool {
+	out, err := h.RunSSHCommand(fmt.Sprintf("command -v %s", cmd))
+	return err == nil && strings.TrimSpace(out) != ""
+}
+
+// installGuestPackage installs pkg on the guest via whichever package manager is present,
+// then re-checks for cmd. It's a best-effort: many minikube ISOs ship neither apt nor yum,
+// in which case the caller falls back to a different mount protocol.
+func installGuestPackage(h *host.Host, pkg, cmd string) bool {
+	if guestHasCommand(h, cmd) {
+		return true
+	}
+	installers := []string{
+		fmt.Sprintf("sudo apt-get update -y >/dev/null 2>&1 && sudo apt-get install -y %s", pkg),
+		fmt.Sprintf("sudo yum install -y %s", pkg),
+		fmt.Sprintf("sudo apk add --no-cache %s", pkg),
+	}
+	for _, cmdLine := range installers {
+		if _, err := h.RunSSHCommand(cmdLine); err == nil && guestHasCommand(h, cmd) {
+			return true
+		}
+	}
+	return false
+}
+
+type ninePMountProvider struct{}
+
+func (ninePMountProvider) Prepare(h *host.Host, mc MountConfig) error { return nil }
+
+func (ninePMountProvider) Command(mc MountConfig) (string, error) {
+	return GetMountCommand(mc.IP, mc.Path, mc.Port, mc.Version, mc.UID, mc.GID, mc.Msize)
+}
+
+func (ninePMountProvider) Cleanup(mc MountConfig) string {
+	return GetMountCleanupCommand(mc.Path)
+}
+
+// reverseTunnels tracks the `ssh -R` processes sshfsMountProvider has opened, keyed by the
+// guest path being mounted, so Cleanup can tear down the same tunnel Prepare started.
+var (
+	reverseTunnelsMu sync.Mutex
+	reverseTunnels   = map[string]*exec.Cmd{}
+)
+
+// sshHostPort is the port on the minikube host that sshd listens on, which the guest reaches
+// through the reverse tunnel instead of dialing the host directly.
+const sshHostPort = "22"
+
+// guestSSHKeyPath is the guest-side keypair sshfs uses to authenticate back to the host over
+// the reverse tunnel, distinct from the host->guest keypair libmachine already manages.
+const guestSSHKeyPath = "/home/docker/.ssh/id_rsa"
+
+type sshfsMountProvider struct{}
+
+// Prepare installs sshfs on the guest if it's missing, confirms the host actually has
+// something for the reverse tunnel to reach, then opens that tunnel over the existing SSH
+// connection so the guest's sshfs client can reach the host's sshd at 127.0.0.1:<mc.Port>
+// without needing a route to the host's real address.
+func (sshfsMountProvider) Prepare(h *host.Host, mc MountConfig) error {
+	if !installGuestPackage(h, "sshfs", "sshfs") {
+		return &UnsupportedMountTypeError{Type: SSHFSMountType, Reason: "sshfs could not be installed on the guest"}
+	}
+	if !hostSSHDRunning() {
+		return &UnsupportedMountTypeError{Type: SSHFSMountType, Reason: "host is not running an sshd for the guest to reverse-tunnel to"}
+	}
+
+	pubKey, err := ensureGuestSSHKeypair(h)
+	if err != nil {
+		return errors.Wrap(err, "ensuring guest ssh keypair")
+	}
+	if err := authorizeGuestKeyOnHost(pubKey); err != nil {
+		return errors.Wrap(err, "authorizing guest key on host")
+	}
+
+	keyPath, err := h.Driver.GetSSHKeyPath()
+	if err != nil {
+		return errors.Wrap(err, "getting ssh key path")
+	}
+	guestIP, err := h.Driver.GetIP()
+	if err != nil {
+		return errors.Wrap(err, "getting guest ip")
+	}
+	sshPort, err := h.Driver.GetSSHPort()
+	if err != nil {
+		return errors.Wrap(err, "getting guest ssh port")
+	}
+
+	reverseTunnelsMu.Lock()
+	defer reverseTunnelsMu.Unlock()
+	if cmd, ok := reverseTunnels[mc.Path]; ok && cmd.Process != nil {
+		return nil
+	}
+
+	cmd := exec.Command("ssh",
+		"-i", keyPath,
+		"-o", "StrictHostKeyChecking=no",
+		"-o", "UserKnownHostsFile=/dev/null",
+		"-p", fmt.Sprintf("%d", sshPort),
+		"-R", fmt.Sprintf("%s:localhost:%s", mc.Port, sshHostPort),
+		"-N",
+		fmt.Sprintf("docker@%s", guestIP))
+	if err := cmd.Start(); err != nil {
+		return errors.Wrap(err, "opening reverse ssh tunnel")
+	}
+	reverseTunnels[mc.Path] = cmd
+	return nil
+}
+
+func (sshfsMountProvider) Command(mc MountConfig) (string, error) {
+	username, err := currentUsername()
+	if err != nil {
+		return "", errors.Wrap(err, "getting current user")
+	}
+	return fmt.Sprintf(
+		"sudo mkdir -p %s || true; "+
+			"sudo sshfs -o reconnect,allow_other,port=%s,IdentityFile=%s,StrictHostKeyChecking=no "+
+			"%s@127.0.0.1:%s %s;",
+		mc.Path, mc.Port, guestSSHKeyPath, username, mc.Path, mc.Path), nil
+}
+
+// hostSSHDRunning reports whether the host has an sshd listening on its loopback interface,
+// which is what the guest's reverse-tunneled sshfs client actually connects to.
+func hostSSHDRunning() bool {
+	conn, err := net.DialTimeout("tcp", "127.0.0.1:"+sshHostPort, 2*time.Second)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// currentUser wraps user.Current with this file's error wrapping convention, so every lookup
+// of the host user mount operations should authenticate and run as goes through one place.
+func currentUser() (*user.User, error) {
+	u, err := user.Current()
+	if err != nil {
+		return nil, errors.Wrap(err, "getting current user")
+	}
+	return u, nil
+}
+
+// currentUsername returns the host user mount operations should authenticate and run as,
+// since minikube always runs as the user whose files are being shared.
+func currentUsername() (string, error) {
+	u, err := currentUser()
+	if err != nil {
+		return "", err
+	}
+	return u.Username, nil
+}
+
+// ensureGuestSSHKeypair returns the guest docker user's SSH public key, generating a
+// passphrase-less keypair at guestSSHKeyPath first if one doesn't already exist, so the
+// reverse-tunneled sshfs client has a key to authenticate with.
+func ensureGuestSSHKeypair(h *host.Host) (string, error) {
+	if _, err := h.RunSSHCommand(fmt.Sprintf("test -f %s", guestSSHKeyPath)); err != nil {
+		if _, err := h.RunSSHCommand(fmt.Sprintf("ssh-keygen -t rsa -N '' -f %s", guestSSHKeyPath)); err != nil {
+			return "", errors.Wrap(err, "generating guest ssh keypair")
+		}
+	}
+	out, err := h.RunSSHCommand(fmt.Sprintf("cat %s.pub", guestSSHKeyPath))
+	if err != nil {
+		return "", errors.Wrap(err, "reading guest ssh public key")
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// authorizeGuestKeyOnHost appends pubKey to the current host user's authorized_keys, if it
+// isn't already present, so the guest's reverse-tunneled sshfs client can authenticate as
+// that user.
+func authorizeGuestKeyOnHost(pubKey string) error {
+	u, err := currentUser()
+	if err != nil {
+		return errors.Wrap(err, "getting current user")
+	}
+	sshDir := filepath.Join(u.HomeDir, ".ssh")
+	if err := os.MkdirAll(sshDir, 0700); err != nil {
+		return errors.Wrapf(err, "creating %s", sshDir)
+	}
+	authKeysPath := filepath.Join(sshDir, "authorized_keys")
+	existing, _ := ioutil.ReadFile(authKeysPath)
+	if strings.Contains(string(existing), pubKey) {
+		return nil
+	}
+	f, err := os.OpenFile(authKeysPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return errors.Wrapf(err, "opening %s", authKeysPath)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(pubKey + "\n"); err != nil {
+		return errors.Wrapf(err, "writing %s", authKeysPath)
+	}
+	return nil
+}
+
+func (sshfsMountProvider) Cleanup(mc MountConfig) string {
+	reverseTunnelsMu.Lock()
+	if cmd, ok := reverseTunnels[mc.Path]; ok {
+		if cmd.Process != nil {
+			cmd.Process.Kill()
+		}
+		delete(reverseTunnels, mc.Path)
+	}
+	reverseTunnelsMu.Unlock()
+	return fmt.Sprintf("sudo fusermount -u %s || sudo umount %s;", mc.Path, mc.Path)
+}
+
+// sambaServers tracks the host-side Samba processes cifsMountProvider has spawned, keyed by
+// the path being shared, so Cleanup can stop the same server Prepare started.
+var (
+	sambaServersMu sync.Mutex
+	sambaServers   = map[string]*exec.Cmd{}
+)
+
+type cifsMountProvider struct{}
+
+// Prepare installs the cifs guest client if it's missing, then spawns a small Samba server
+// on the host to share mc.Path, since the guest's `mount -t cifs` needs something listening.
+func (cifsMountProvider) Prepare(h *host.Host, mc MountConfig) error {
+	if !installGuestPackage(h, "cifs-utils", "mount.cifs") {
+		return &UnsupportedMountTypeError{Type: CIFSMountType, Reason: "mount.cifs could not be installed on the guest"}
+	}
+
+	sambaServersMu.Lock()
+	defer sambaServersMu.Unlock()
+	if cmd, ok := sambaServers[mc.Path]; ok && cmd.Process != nil {
+		return nil
+	}
+
+	confPath, err := writeSambaConf(mc.Path, mc.IP)
+	if err != nil {
+		return errors.Wrap(err, "writing samba config")
+	}
+
+	cmd := exec.Command("smbd", "--foreground", "--no-process-group", "-s", confPath)
+	if err := cmd.Start(); err != nil {
+		return errors.Wrap(err, "starting samba server")
+	}
+	sambaServers[mc.Path] = cmd
+	return nil
+}
+
+// writeSambaConf renders a minimal smb.conf sharing path, bound only to bindIP (the host-only
+// interface the guest reaches the host on) so the share isn't reachable from other networks
+// the host happens to be attached to, and returns the conf's path on disk.
+func writeSambaConf(path string, bindIP net.IP) (string, error) {
+	username, err := currentUsername()
+	if err != nil {
+		return "", errors.Wrap(err, "getting current user")
+	}
+	confPath := filepath.Join(constants.GetMinipath(), "smb-"+sambaShareName(path)+".conf")
+	conf := fmt.Sprintf(`[global]
+workgroup = WORKGROUP
+server string = minikube mount
+security = user
+map to guest = Bad User
+interfaces = %s
+bind interfaces only = yes
+pid directory = %s
+
+[%s]
+path = %s
+browsable = yes
+guest ok = yes
+read only = no
+force user = %s
+`, bindIP, constants.GetMinipath(), sambaShareName(path), path, username)
+	return confPath, ioutil.WriteFile(confPath, []byte(conf), 0644)
+}
+
+// sambaShareName derives a Samba share name from a mount path, since share names can't
+// contain path separators.
+func sambaShareName(path string) string {
+	return strings.Trim(strings.Replace(path, "/", "_", -1), "_")
+}
+
+func (cifsMountProvider) Command(mc MountConfig) (string, error) {
+	return fmt.Sprintf(
+		"sudo mkdir -p %s || true; sudo mount -t cifs -o guest,uid=%d,gid=%d //%s/%s %s;",
+		mc.Path, mc.UID, mc.GID, mc.IP, sambaShareName(mc.Path), mc.Path), nil
+}
+
+func (cifsMountProvider) Cleanup(mc MountConfig) string {
+	sambaServersMu.Lock()
+	if cmd, ok := sambaServers[mc.Path]; ok {
+		if cmd.Process != nil {
+			cmd.Process.Kill()
+		}
+		delete(sambaServers, mc.Path)
+	}
+	sambaServersMu.Unlock()
+	return GetMountCleanupCommand(mc.Path)
+}
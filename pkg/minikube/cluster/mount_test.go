@@ -0,0 +1,66 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestShouldFallbackToNineP(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		mc   MountType
+		want bool
+	}{
+		{"unsupported sshfs falls back", &UnsupportedMountTypeError{Type: SSHFSMountType}, SSHFSMountType, true},
+		{"unsupported cifs falls back", &UnsupportedMountTypeError{Type: CIFSMountType}, CIFSMountType, true},
+		{"unsupported 9p does not loop", &UnsupportedMountTypeError{Type: NinePMountType}, NinePMountType, false},
+		{"other errors do not fall back", errors.New("ssh timeout"), SSHFSMountType, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shouldFallbackToNineP(tt.err, tt.mc); got != tt.want {
+				t.Errorf("shouldFallbackToNineP(%v, %v) = %v, want %v", tt.err, tt.mc, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCurrentUsername(t *testing.T) {
+	username, err := currentUsername()
+	if err != nil {
+		t.Fatalf("currentUsername() error = %v", err)
+	}
+	if username == "" {
+		t.Error("currentUsername() = \"\", want a non-empty username")
+	}
+}
+
+func TestSambaShareName(t *testing.T) {
+	tests := []struct{ path, want string }{
+		{"/home/user/code", "home_user_code"},
+		{"/", ""},
+		{"/mnt", "mnt"},
+	}
+	for _, tt := range tests {
+		if got := sambaShareName(tt.path); got != tt.want {
+			t.Errorf("sambaShareName(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/docker/machine/libmachine"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	cfg "k8s.io/minikube/pkg/minikube/config"
+)
+
+// kubeadmTokenRe matches the join token printed by `kubeadm init`.
+var kubeadmTokenRe = regexp.MustCompile(`--token (\S+)`)
+
+// NodeRole describes the role a node plays within a cluster.
+type NodeRole string
+
+const (
+	// ControlPlane is the node that runs `kubeadm init` and the API server.
+	ControlPlane NodeRole = "control-plane"
+	// Worker is a node that joins the cluster with `kubeadm join`.
+	Worker NodeRole = "worker"
+)
+
+// NodeConfig describes a single VM within a multi-node cluster.
+type NodeConfig struct {
+	// Name is the machine name used to look the host up via the libmachine API.
+	Name string
+	// Role is whether this node is the control-plane or a worker.
+	Role NodeRole
+	// Index is the node's position within the cluster (0 is always the control-plane).
+	Index int
+	// KubeadmToken is the join token discovered from the control-plane's `kubeadm init` output.
+	KubeadmToken string
+}
+
+// controlPlaneIndex returns the index of the NodeConfig with Role == ControlPlane, or an
+// error if none is found. It returns an index, rather than a copy, so callers can persist
+// state discovered during StartCluster (such as KubeadmToken) back onto the caller's slice.
+func controlPlaneIndex(nodes []NodeConfig) (int, error) {
+	for i, n := range nodes {
+		if n.Role == ControlPlane {
+			return i, nil
+		}
+	}
+	return 0, errors.New("no control-plane node in cluster config")
+}
+
+// StartCluster provisions every node in the cluster concurrently, then initializes
+// Kubernetes on the control-plane node and joins the remaining nodes to it. If any node
+// fails to provision, or kubeadm init/join fails, every VM this call started is rolled
+// back so a failed StartCluster doesn't leave orphaned running VMs behind.
+func StartCluster(ctx context.Context, api libmachine.API, config cfg.MachineConfig, nodes []NodeConfig) (map[string]*host.Host, error) {
+	emitter := emitterFromContext(ctx)
+
+	cpIdx, err := controlPlaneIndex(nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	hosts := map[string]*host.Host{}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	errs := make([]error, len(nodes))
+
+	for i, n := range nodes {
+		wg.Add(1)
+		go func(i int, n NodeConfig) {
+			defer wg.Done()
+			h, err := startNode(ctx, api, config, n)
+			if err != nil {
+				errs[i] = errors.Wrapf(err, "starting node %s", n.Name)
+				return
+			}
+			mu.Lock()
+			hosts[n.Name] = h
+			mu.Unlock()
+		}(i, n)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			rollbackPartialCluster(ctx, api, hosts)
+			return hosts, err
+		}
+	}
+
+	cp := nodes[cpIdx]
+	cpHost := hosts[cp.Name]
+
+	emitter.Emit(Event{Type: KubeadmInitStarted, MachineName: cp.Name, Message: fmt.Sprintf("Initializing Kubernetes on control-plane node %q ...", cp.Name)})
+	cpStart := time.Now()
+	token, err := kubeadmInit(cpHost)
+	if err != nil {
+		err = errors.Wrap(err, "kubeadm init")
+		emitter.Emit(Event{Type: Error, MachineName: cp.Name, Message: err.Error(), Err: err})
+		rollbackPartialCluster(ctx, api, hosts)
+		return hosts, err
+	}
+	nodes[cpIdx].KubeadmToken = token
+	emitter.Emit(Event{Type: KubeadmInitDone, MachineName: cp.Name, Duration: time.Since(cpStart),
+		Message: fmt.Sprintf("Initialized Kubernetes on control-plane node %q", cp.Name)})
+
+	apiServerIP, err := GetHostDriverIP(api, cp.Name)
+	if err != nil {
+		err = errors.Wrap(err, "getting control-plane IP")
+		emitter.Emit(Event{Type: Error, MachineName: cp.Name, Message: err.Error(), Err: err})
+		rollbackPartialCluster(ctx, api, hosts)
+		return hosts, err
+	}
+
+	for i, n := range nodes {
+		if n.Role == ControlPlane {
+			continue
+		}
+		emitter.Emit(Event{Type: KubeadmJoinStarted, MachineName: n.Name, Message: fmt.Sprintf("Joining node %q to the cluster ...", n.Name)})
+		joinStart := time.Now()
+		if err := kubeadmJoin(hosts[n.Name], apiServerIP, token); err != nil {
+			err = errors.Wrapf(err, "joining node %s", n.Name)
+			emitter.Emit(Event{Type: Error, MachineName: n.Name, Message: err.Error(), Err: err})
+			rollbackPartialCluster(ctx, api, hosts)
+			return hosts, err
+		}
+		nodes[i].KubeadmToken = token
+		emitter.Emit(Event{Type: KubeadmJoinDone, MachineName: n.Name, Duration: time.Since(joinStart),
+			Message: fmt.Sprintf("Joined node %q to the cluster", n.Name)})
+	}
+	return hosts, nil
+}
+
+// rollbackPartialCluster deletes every VM StartCluster managed to start this call, so a
+// failure partway through doesn't leave orphaned running VMs behind. Best-effort: a
+// deletion failure is logged, not returned, so it doesn't mask the original error.
+func rollbackPartialCluster(ctx context.Context, api libmachine.API, hosts map[string]*host.Host) {
+	for name := range hosts {
+		glog.Warningf("StartCluster failed, rolling back node %s", name)
+		if err := DeleteNamedHost(ctx, api, name); err != nil {
+			glog.Warningf("rollback: failed to delete node %s: %v", name, err)
+		}
+	}
+}
+
+// startNode brings up a single node's VM, reusing the single-node StartNamedHost logic.
+func startNode(ctx context.Context, api libmachine.API, config cfg.MachineConfig, n NodeConfig) (*host.Host, error) {
+	glog.Infof("Provisioning node %s (role=%s, index=%d) with config: %+v", n.Name, n.Role, n.Index, config)
+	return StartNamedHost(ctx, api, n.Name, config)
+}
+
+// kubeadmInit runs `kubeadm init` on the control-plane node and returns the discovered join token.
+func kubeadmInit(h *host.Host) (string, error) {
+	out, err := h.RunSSHCommand("sudo kubeadm init --ignore-preflight-errors=all")
+	if err != nil {
+		return "", errors.Wrap(err, "kubeadm init")
+	}
+	token, err := parseKubeadmToken(out)
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// parseKubeadmToken extracts the join token from `kubeadm init`'s output, which prints
+// the full `kubeadm join ... --token <token> ...` command for operators to copy.
+func parseKubeadmToken(out string) (string, error) {
+	m := kubeadmTokenRe.FindStringSubmatch(out)
+	if m == nil {
+		return "", errors.New("unable to find kubeadm join token in kubeadm init output")
+	}
+	return m[1], nil
+}
+
+// kubeadmJoin runs `kubeadm join` on a worker node against the discovered API server IP.
+func kubeadmJoin(h *host.Host, apiServerIP net.IP, token string) error {
+	cmd := fmt.Sprintf("sudo kubeadm join %s:6443 --token %s --discovery-token-unsafe-skip-ca-verification", apiServerIP, token)
+	_, err := h.RunSSHCommand(cmd)
+	return err
+}
+
+// StopCluster stops every node VM in the cluster. Workers are stopped before the
+// control-plane so that kubeadm doesn't see a half-torn-down API server.
+func StopCluster(ctx context.Context, api libmachine.API, nodes []NodeConfig) error {
+	for _, n := range orderedForTeardown(nodes) {
+		if err := StopNamedHost(ctx, api, n.Name); err != nil {
+			return errors.Wrapf(err, "stop node %s", n.Name)
+		}
+	}
+	return nil
+}
+
+// DeleteCluster deletes every node VM in the cluster, workers first.
+func DeleteCluster(ctx context.Context, api libmachine.API, nodes []NodeConfig) error {
+	for _, n := range orderedForTeardown(nodes) {
+		if err := DeleteNamedHost(ctx, api, n.Name); err != nil {
+			return errors.Wrapf(err, "delete node %s", n.Name)
+		}
+	}
+	return nil
+}
+
+// orderedForTeardown returns nodes with workers before the control-plane.
+func orderedForTeardown(nodes []NodeConfig) []NodeConfig {
+	ordered := make([]NodeConfig, 0, len(nodes))
+	for _, n := range nodes {
+		if n.Role != ControlPlane {
+			ordered = append(ordered, n)
+		}
+	}
+	for _, n := range nodes {
+		if n.Role == ControlPlane {
+			ordered = append(ordered, n)
+		}
+	}
+	return ordered
+}
+
+// GetClusterStatus returns the libmachine state of every node in the cluster, keyed by node name.
+func GetClusterStatus(api libmachine.API, nodes []NodeConfig) (map[string]string, error) {
+	statuses := map[string]string{}
+	for _, n := range nodes {
+		s, err := GetHostStatus(api, n.Name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "status for node %s", n.Name)
+		}
+		statuses[n.Name] = s
+	}
+	return statuses, nil
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import "testing"
+
+func TestParseKubeadmToken(t *testing.T) {
+	out := "...\nkubeadm join 192.168.99.100:6443 --token abcdef.0123456789abcdef --discovery-token-ca-cert-hash sha256:deadbeef\n"
+	token, err := parseKubeadmToken(out)
+	if err != nil {
+		t.Fatalf("parseKubeadmToken: %v", err)
+	}
+	if want := "abcdef.0123456789abcdef"; token != want {
+		t.Errorf("parseKubeadmToken() = %q, want %q", token, want)
+	}
+}
+
+func TestParseKubeadmTokenMissing(t *testing.T) {
+	if _, err := parseKubeadmToken("kubeadm init failed: connection refused"); err == nil {
+		t.Fatal("expected an error when no token is present in the output")
+	}
+}
+
+func TestControlPlaneIndex(t *testing.T) {
+	nodes := []NodeConfig{
+		{Name: "minikube-m02", Role: Worker, Index: 1},
+		{Name: "minikube", Role: ControlPlane, Index: 0},
+	}
+	idx, err := controlPlaneIndex(nodes)
+	if err != nil {
+		t.Fatalf("controlPlaneIndex: %v", err)
+	}
+	if nodes[idx].Name != "minikube" {
+		t.Errorf("controlPlaneIndex() = %d (%s), want the control-plane node", idx, nodes[idx].Name)
+	}
+
+	if _, err := controlPlaneIndex([]NodeConfig{{Name: "minikube-m02", Role: Worker}}); err == nil {
+		t.Fatal("expected an error when no control-plane node is present")
+	}
+}
+
+func TestOrderedForTeardown(t *testing.T) {
+	nodes := []NodeConfig{
+		{Name: "minikube", Role: ControlPlane},
+		{Name: "minikube-m02", Role: Worker},
+		{Name: "minikube-m03", Role: Worker},
+	}
+	ordered := orderedForTeardown(nodes)
+	if len(ordered) != len(nodes) {
+		t.Fatalf("orderedForTeardown() returned %d nodes, want %d", len(ordered), len(nodes))
+	}
+	if ordered[len(ordered)-1].Role != ControlPlane {
+		t.Errorf("orderedForTeardown() control-plane node is not last: %+v", ordered)
+	}
+	for _, n := range ordered[:len(ordered)-1] {
+		if n.Role != Worker {
+			t.Errorf("orderedForTeardown() worker %q appeared after the control-plane", n.Name)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/docker/machine/libmachine"
+	"github.com/docker/machine/libmachine/host"
+	"github.com/pkg/errors"
+	cfg "k8s.io/minikube/pkg/minikube/config"
+	"k8s.io/minikube/pkg/minikube/console"
+	"k8s.io/minikube/pkg/minikube/constants"
+)
+
+// SnapshotMetadata is written alongside the driver's native snapshot and carries everything
+// needed to make sense of it again on restore: the config the VM was created with, its
+// kubeconfig, and the set of addons that were enabled.
+type SnapshotMetadata struct {
+	MachineName string            `json:"machineName"`
+	Config      cfg.MachineConfig `json:"config"`
+	KubeConfig  []byte            `json:"kubeConfig"`
+	Addons      map[string]bool   `json:"addons"`
+	CreatedAt   time.Time         `json:"createdAt"`
+}
+
+// SnapshotProvider captures and restores a driver's native VM disk state. Each driver that
+// supports snapshots implements this once and registers it in snapshotProviders.
+type SnapshotProvider interface {
+	// Save captures the current disk state of h under the given snapshot name.
+	Save(h *host.Host, name string) error
+	// Restore rolls h's disk state back to the given snapshot name.
+	Restore(h *host.Host, name string) error
+}
+
+var snapshotProviders = map[string]SnapshotProvider{
+	"virtualbox": virtualboxSnapshotProvider{},
+	"kvm2":       kvm2SnapshotProvider{},
+	"hyperkit":   hyperkitSnapshotProvider{},
+	"hyperv":     hypervSnapshotProvider{},
+}
+
+// snapshotMetadataPath returns where a snapshot's metadata JSON is stored on the host running
+// minikube, alongside the rest of minikube's per-machine state.
+func snapshotMetadataPath(machineName, name string) string {
+	return filepath.Join(constants.GetMinipath(), "snapshots", machineName, name+".json")
+}
+
+// SnapshotHost captures h's disk state using its driver's native mechanism, plus a metadata
+// JSON containing the MachineConfig, kubeconfig, and addon state.
+func SnapshotHost(api libmachine.API, name string, config cfg.MachineConfig, kubeConfig []byte, addons map[string]bool) error {
+	return SnapshotNamedHost(api, cfg.GetMachineName(), name, config, kubeConfig, addons)
+}
+
+// SnapshotNamedHost is SnapshotHost for a specific node in a multi-node cluster. config,
+// kubeConfig and addons are captured into the snapshot's metadata JSON so that RestoreHost
+// has enough context to make sense of the snapshot without a full re-provision.
+func SnapshotNamedHost(api libmachine.API, machineName, name string, config cfg.MachineConfig, kubeConfig []byte, addons map[string]bool) error {
+	h, err := CheckIfHostExistsAndLoad(api, machineName)
+	if err != nil {
+		return errors.Wrap(err, "load")
+	}
+
+	provider, ok := snapshotProviders[h.DriverName]
+	if !ok {
+		return errors.Errorf("driver %q does not support snapshots", h.DriverName)
+	}
+
+	console.OutStyle("starting-vm", "Snapshotting %q as %q ...", machineName, name)
+	if err := provider.Save(h, name); err != nil {
+		return errors.Wrap(err, "snapshot save")
+	}
+
+	meta := SnapshotMetadata{
+		MachineName: machineName,
+		Config:      config,
+		KubeConfig:  kubeConfig,
+		Addons:      addons,
+		CreatedAt:   time.Now(),
+	}
+	if err := writeSnapshotMetadata(machineName, name, meta); err != nil {
+		return errors.Wrap(err, "writing snapshot metadata")
+	}
+	return nil
+}
+
+// RestoreHost rolls h's disk state back to a previously captured snapshot, using the same
+// driver-specific mechanism that captured it, and returns the metadata captured at snapshot
+// time so the caller can restore the MachineConfig, kubeconfig and addon state too.
+func RestoreHost(api libmachine.API, name string) (SnapshotMetadata, error) {
+	return RestoreNamedHost(api, cfg.GetMachineName(), name)
+}
+
+// RestoreNamedHost is RestoreHost for a specific node in a multi-node cluster.
+func RestoreNamedHost(api libmachine.API, machineName, name string) (SnapshotMetadata, error) {
+	h, err := CheckIfHostExistsAndLoad(api, machineName)
+	if err != nil {
+		return SnapshotMetadata{}, errors.Wrap(err, "load")
+	}
+
+	meta, err := readSnapshotMetadata(machineName, name)
+	if err != nil {
+		return SnapshotMetadata{}, errors.Wrap(err, "reading snapshot metadata")
+	}
+
+	provider, ok := snapshotProviders[h.DriverName]
+	if !ok {
+		return SnapshotMetadata{}, errors.Errorf("driver %q does not support snapshots", h.DriverName)
+	}
+
+	console.OutStyle("restarting", "Restoring %q to snapshot %q ...", machineName, name)
+	if err := provider.Restore(h, name); err != nil {
+		return SnapshotMetadata{}, errors.Wrap(err, "snapshot restore")
+	}
+	return meta, nil
+}
+
+func writeSnapshotMetadata(machineName, name string, meta SnapshotMetadata) error {
+	path := snapshotMetadataPath(machineName, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return errors.Wrapf(err, "creating %s", filepath.Dir(path))
+	}
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "marshal")
+	}
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		return errors.Wrapf(err, "writing %s", path)
+	}
+	return nil
+}
+
+func readSnapshotMetadata(machineName, name string) (SnapshotMetadata, error) {
+	path := snapshotMetadataPath(machineName, name)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return SnapshotMetadata{}, errors.Wrapf(err, "reading %s", path)
+	}
+	var meta SnapshotMetadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return SnapshotMetadata{}, errors.Wrap(err, "unmarshal")
+	}
+	return meta, nil
+}
+
+type virtualboxSnapshotProvider struct{}
+
+func (virtualboxSnapshotProvider) Save(h *host.Host, name string) error {
+	return exec.Command(detectVBoxManageCmd(), "snapshot", h.Name, "take", name).Run()
+}
+
+func (virtualboxSnapshotProvider) Restore(h *host.Host, name string) error {
+	return exec.Command(detectVBoxManageCmd(), "snapshot", h.Name, "restore", name).Run()
+}
+
+type kvm2SnapshotProvider struct{}
+
+func (kvm2SnapshotProvider) Save(h *host.Host, name string) error {
+	return exec.Command("virsh", "-c", "qemu:///system", "snapshot-create-as", h.Name, name).Run()
+}
+
+func (kvm2SnapshotProvider) Restore(h *host.Host, name string) error {
+	return exec.Command("virsh", "-c", "qemu:///system", "snapshot-revert", h.Name, name).Run()
+}
+
+// hyperkitSnapshotProvider snapshots by cloning hyperkit's raw disk image rather than invoking
+// an external snapshot tool, since hyperkit itself has no snapshot command. hyperkit only runs
+// on macOS, so this shells out to the platform's BSD `cp` and its APFS clonefile flag (`-c`)
+// rather than GNU coreutils' `--reflink`, which BSD cp doesn't have.
+type hyperkitSnapshotProvider struct{}
+
+func (hyperkitSnapshotProvider) Save(h *host.Host, name string) error {
+	disk := filepath.Join(constants.GetMinipath(), "machines", h.Name, "disk.img")
+	dest := filepath.Join(constants.GetMinipath(), "machines", h.Name, "snapshot-"+name+".img")
+	return exec.Command("cp", "-c", disk, dest).Run()
+}
+
+func (hyperkitSnapshotProvider) Restore(h *host.Host, name string) error {
+	disk := filepath.Join(constants.GetMinipath(), "machines", h.Name, "disk.img")
+	src := filepath.Join(constants.GetMinipath(), "machines", h.Name, "snapshot-"+name+".img")
+	return exec.Command("cp", "-c", src, disk).Run()
+}
+
+type hypervSnapshotProvider struct{}
+
+func (hypervSnapshotProvider) Save(h *host.Host, name string) error {
+	return exec.Command("powershell", "-Command", "Checkpoint-VM", "-Name", h.Name, "-SnapshotName", name).Run()
+}
+
+func (hypervSnapshotProvider) Restore(h *host.Host, name string) error {
+	return exec.Command("powershell", "-Command",
+		"Restore-VMSnapshot", "-VMName", h.Name, "-Name", name, "-Confirm:$false").Run()
+}
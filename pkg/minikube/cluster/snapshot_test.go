@@ -0,0 +1,62 @@
+/*
+Copyright 2016 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	cfg "k8s.io/minikube/pkg/minikube/config"
+)
+
+func TestSnapshotMetadataRoundTrip(t *testing.T) {
+	os.Setenv("MINIKUBE_HOME", t.TempDir())
+	defer os.Unsetenv("MINIKUBE_HOME")
+
+	want := SnapshotMetadata{
+		MachineName: "minikube",
+		Config:      cfg.MachineConfig{VMDriver: "virtualbox", CPUs: 2},
+		KubeConfig:  []byte("fake-kubeconfig"),
+		Addons:      map[string]bool{"dashboard": true},
+		CreatedAt:   time.Now().Round(time.Second),
+	}
+
+	if err := writeSnapshotMetadata("minikube", "snap1", want); err != nil {
+		t.Fatalf("writeSnapshotMetadata: %v", err)
+	}
+
+	got, err := readSnapshotMetadata("minikube", "snap1")
+	if err != nil {
+		t.Fatalf("readSnapshotMetadata: %v", err)
+	}
+
+	if got.MachineName != want.MachineName || got.Config.VMDriver != want.Config.VMDriver ||
+		string(got.KubeConfig) != string(want.KubeConfig) || !got.Addons["dashboard"] ||
+		!got.CreatedAt.Equal(want.CreatedAt) {
+		t.Fatalf("round-tripped metadata = %+v, want %+v", got, want)
+	}
+}
+
+func TestReadSnapshotMetadataMissing(t *testing.T) {
+	os.Setenv("MINIKUBE_HOME", t.TempDir())
+	defer os.Unsetenv("MINIKUBE_HOME")
+
+	if _, err := readSnapshotMetadata("minikube", "does-not-exist"); err == nil {
+		t.Fatal("expected an error reading a snapshot that was never written")
+	}
+}